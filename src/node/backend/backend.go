@@ -0,0 +1,47 @@
+// Package backend defines the storage backend abstraction used by Node
+// to persist records.
+//
+// Package backend определяет абстракцию хранилища, используемую Node
+// для хранения записей.
+package backend
+
+import "storage"
+
+// Backend is a pluggable storage engine for a Node. Implementations are
+// free to keep data in memory or persist it to disk, as long as they
+// satisfy this interface.
+//
+// Backend -- подключаемый движок хранения для Node. Реализация может
+// хранить данные в памяти или на диске, лишь бы она удовлетворяла
+// данному интерфейсу.
+type Backend interface {
+	// Put stores d under k, overwriting any previous value.
+	// Put -- сохранить d под ключом k, перезаписывая предыдущее значение.
+	Put(k storage.RecordID, d []byte) error
+
+	// Get returns the value stored under k, or storage.ErrRecordNotFound
+	// if there is none.
+	// Get -- вернуть значение, хранящееся под ключом k, или
+	// storage.ErrRecordNotFound, если такого нет.
+	Get(k storage.RecordID) ([]byte, error)
+
+	// Del removes the value stored under k, or returns
+	// storage.ErrRecordNotFound if there is none.
+	// Del -- удалить значение, хранящееся под ключом k, или вернуть
+	// storage.ErrRecordNotFound, если такого нет.
+	Del(k storage.RecordID) error
+
+	// Iterate calls f for every record currently stored in the backend.
+	// Iteration stops and Iterate returns the error as soon as f returns
+	// a non-nil error.
+	// Iterate -- вызвать f для каждой записи, хранящейся в backend.
+	// Итерация останавливается и Iterate возвращает ошибку, как только
+	// f вернёт ненулевую ошибку.
+	Iterate(f func(k storage.RecordID, d []byte) error) error
+
+	// Close releases any resources held by the backend. A closed
+	// backend must not be used again.
+	// Close -- освободить ресурсы, занятые backend. Закрытый backend
+	// повторно использовать нельзя.
+	Close() error
+}
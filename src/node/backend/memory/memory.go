@@ -0,0 +1,78 @@
+// Package memory implements an in-memory backend.Backend. It is the
+// default backend used by Node when no other backend is configured; it
+// keeps no state across restarts.
+//
+// Package memory реализует backend.Backend в памяти. Это backend по
+// умолчанию, используемый Node, если другой backend не задан; при
+// перезапуске все данные теряются.
+package memory
+
+import (
+	"sync"
+
+	"node/backend"
+	"storage"
+)
+
+// Backend is a backend.Backend backed by a plain Go map.
+//
+// Backend -- backend.Backend, хранящий данные в обычной map.
+type Backend struct {
+	lock sync.Mutex
+	data map[storage.RecordID][]byte
+}
+
+// New creates a new, empty Backend.
+//
+// New создает новый, пустой Backend.
+func New() *Backend {
+	return &Backend{data: make(map[storage.RecordID][]byte)}
+}
+
+// Put implements backend.Backend.
+func (b *Backend) Put(k storage.RecordID, d []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.data[k] = d
+	return nil
+}
+
+// Get implements backend.Backend.
+func (b *Backend) Get(k storage.RecordID) ([]byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if d, ok := b.data[k]; ok {
+		return d, nil
+	}
+	return nil, storage.ErrRecordNotFound
+}
+
+// Del implements backend.Backend.
+func (b *Backend) Del(k storage.RecordID) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.data[k]; ok {
+		delete(b.data, k)
+		return nil
+	}
+	return storage.ErrRecordNotFound
+}
+
+// Iterate implements backend.Backend.
+func (b *Backend) Iterate(f func(k storage.RecordID, d []byte) error) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for k, d := range b.data {
+		if err := f(k, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements backend.Backend. It is a no-op for the memory backend.
+func (b *Backend) Close() error {
+	return nil
+}
+
+var _ backend.Backend = (*Backend)(nil)
@@ -0,0 +1,57 @@
+// Package client defines the client interface Nodes use to talk to one
+// another, mirroring the router/client pattern used for Node-to-Router
+// calls.
+//
+// Package client определяет интерфейс клиента, через который Node
+// общаются друг с другом -- по тому же принципу, что router/client
+// используется для вызовов Node-to-Router.
+package client
+
+import "storage"
+
+// BucketEntry identifies a single record by its ID and the sha256 of
+// its value, without transferring the value itself. It is used by
+// anti-entropy sync to diff two Nodes' key-spaces bucket by bucket.
+//
+// BucketEntry идентифицирует запись по ее ID и sha256 от значения, не
+// передавая само значение. Используется anti-entropy sync для сравнения
+// ключевых пространств двух Node по bucket-ам.
+type BucketEntry struct {
+	ID   storage.RecordID
+	Hash [32]byte
+}
+
+// NodeClient issues Put/Del RPCs against a remote Node, plus the
+// digest/entry/fetch calls anti-entropy sync needs to reconcile two
+// Nodes' key-spaces.
+//
+// NodeClient выполняет Put/Del RPC к удаленному Node, а также вызовы
+// digest/entry/fetch, необходимые anti-entropy sync для сверки ключевых
+// пространств двух Node.
+type NodeClient interface {
+	// Put asks the Node at addr to store d under k.
+	// Put -- попросить Node по адресу addr сохранить d под ключом k.
+	Put(addr storage.ServiceAddr, k storage.RecordID, d []byte) error
+
+	// Del asks the Node at addr to remove the record stored under k.
+	// Del -- попросить Node по адресу addr удалить запись, хранящуюся
+	// под ключом k.
+	Del(addr storage.ServiceAddr, k storage.RecordID) error
+
+	// Get fetches the raw value for k from the Node at addr.
+	// Get -- получить необработанное значение для k от Node по адресу
+	// addr.
+	Get(addr storage.ServiceAddr, k storage.RecordID) ([]byte, error)
+
+	// BucketDigests returns the Node at addr's per-bucket XOR-of-sha256
+	// digests.
+	// BucketDigests возвращает digest-и (XOR-of-sha256) по bucket-ам от
+	// Node по адресу addr.
+	BucketDigests(addr storage.ServiceAddr) (map[uint8][]byte, error)
+
+	// BucketEntries returns the Node at addr's sorted (RecordID, value
+	// hash) list for bucket.
+	// BucketEntries возвращает отсортированный список (RecordID, hash
+	// значения) для bucket от Node по адресу addr.
+	BucketEntries(addr storage.ServiceAddr, bucket uint8) ([]BucketEntry, error)
+}
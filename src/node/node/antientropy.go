@@ -0,0 +1,223 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"node/client"
+	"storage"
+)
+
+// numBuckets is the number of key-space buckets anti-entropy sync
+// splits the RecordID space into, keyed by the first byte of
+// sha256(id).
+const numBuckets = 256
+
+// defaultAntiEntropyInterval is used when Config.AntiEntropyInterval is
+// zero.
+const defaultAntiEntropyInterval = 5 * time.Minute
+
+func bucketOf(k storage.RecordID) uint8 {
+	return sha256.Sum256(recordIDBytes(k))[0]
+}
+
+func recordIDBytes(k storage.RecordID) []byte {
+	return []byte(fmt.Sprintf("%v", k))
+}
+
+// BucketDigests returns a copy of the running per-bucket XOR-of-sha256
+// digests, so a sibling Node can tell which buckets, if any, have
+// diverged from its own without transferring the full key-space.
+//
+// BucketDigests возвращает копию текущих digest-ов (XOR-of-sha256) по
+// bucket-ам, чтобы соседний Node мог понять, какие bucket-ы разошлись с
+// его собственными, не передавая все ключевое пространство.
+func (node *Node) BucketDigests() map[uint8][]byte {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	out := make(map[uint8][]byte, numBuckets)
+	for b, d := range node.bucketDigest {
+		cp := d
+		out[uint8(b)] = cp[:]
+	}
+	return out
+}
+
+// BucketEntries returns the sorted (RecordID, sha256(value)) list for
+// every record whose key hashes into bucket. Like Get, a record whose
+// TTL has elapsed but that the garbage collector hasn't removed yet is
+// masked as absent, so peers never try to pull a record that would just
+// bounce off ErrRecordNotFound.
+//
+// BucketEntries возвращает отсортированный список (RecordID,
+// sha256(value)) для всех записей, чей ключ попадает в bucket. Как и
+// Get, запись, чей TTL истек, но которую еще не удалил garbage
+// collector, маскируется как отсутствующая, чтобы peer не пытался
+// получить запись, которая просто вернет ErrRecordNotFound.
+func (node *Node) BucketEntries(bucket uint8) ([]client.BucketEntry, error) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	now := time.Now()
+	var entries []client.BucketEntry
+	err := node.backend.Iterate(func(k storage.RecordID, raw []byte) error {
+		if bucketOf(k) != bucket {
+			return nil
+		}
+		if exp, ok := node.expiry[k]; ok && !now.Before(exp) {
+			return nil
+		}
+		d, _, err := decodeRecord(raw)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, client.BucketEntry{ID: k, Hash: sha256.Sum256(d)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return fmt.Sprint(entries[i].ID) < fmt.Sprint(entries[j].ID)
+	})
+	return entries, nil
+}
+
+// xorDigest folds d into (or, applied twice, back out of) the digest of
+// the bucket k belongs to. Callers must hold node.lock and must call it
+// exactly once for each value added to or removed from the backend, so
+// an overwrite calls it twice: once for the old value, once for the new.
+func (node *Node) xorDigest(k storage.RecordID, d []byte) {
+	b := bucketOf(k)
+	h := sha256.Sum256(d)
+	cur := node.bucketDigest[b]
+	for i := range cur {
+		cur[i] ^= h[i]
+	}
+	node.bucketDigest[b] = cur
+}
+
+// antiEntropyService is the built-in Service that periodically
+// reconciles this Node's key-space against a sibling replica, so a Node
+// that missed writes while offline catches up without a full data
+// reshuffle.
+//
+// antiEntropyService -- встроенный Service, периодически сверяющий
+// ключевое пространство этого Node с соседней репликой, чтобы Node,
+// пропустивший записи, пока был офлайн, догнал состояние без полного
+// переразбиения данных.
+type antiEntropyService struct {
+	node     *Node
+	interval time.Duration
+	done     chan struct{}
+}
+
+func newAntiEntropyService(node *Node) (Service, error) {
+	interval := node.cfg.AntiEntropyInterval
+	if interval <= 0 {
+		interval = defaultAntiEntropyInterval
+	}
+	return &antiEntropyService{node: node, interval: interval}, nil
+}
+
+// Start implements Service.
+func (a *antiEntropyService) Start(ctx context.Context) error {
+	a.done = make(chan struct{})
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.runPass()
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop implements Service.
+func (a *antiEntropyService) Stop() error {
+	<-a.done
+	return nil
+}
+
+func (a *antiEntropyService) runPass() {
+	node := a.node
+	all, err := node.cfg.Client.List()
+	if err != nil {
+		log.Printf("node: anti-entropy: listing peers failed: %v", err)
+		return
+	}
+	peers := node.excludeSelf(all)
+	if len(peers) == 0 {
+		return
+	}
+	peer := peers[0]
+
+	theirs, err := node.cfg.NodeClient.BucketDigests(peer)
+	if err != nil {
+		log.Printf("node: anti-entropy: digest exchange with %v failed: %v", peer, err)
+		return
+	}
+	mine := node.BucketDigests()
+
+	for bucket, mineDigest := range mine {
+		if bytes.Equal(mineDigest, theirs[bucket]) {
+			continue
+		}
+		if err := a.syncBucket(peer, bucket); err != nil {
+			log.Printf("node: anti-entropy: bucket %d sync with %v failed: %v", bucket, peer, err)
+		}
+	}
+}
+
+// syncBucket pulls every record from peer's bucket that this Node is
+// missing or holds a conflicting value for. Conflicts (same key,
+// different value) are resolved by preferring the lexicographically
+// larger value hash.
+func (a *antiEntropyService) syncBucket(peer storage.ServiceAddr, bucket uint8) error {
+	node := a.node
+	theirEntries, err := node.cfg.NodeClient.BucketEntries(peer, bucket)
+	if err != nil {
+		return err
+	}
+	mineEntries, err := node.BucketEntries(bucket)
+	if err != nil {
+		return err
+	}
+	mine := make(map[storage.RecordID][32]byte, len(mineEntries))
+	for _, e := range mineEntries {
+		mine[e.ID] = e.Hash
+	}
+
+	for _, theirs := range theirEntries {
+		mineHash, have := mine[theirs.ID]
+		if have {
+			if mineHash == theirs.Hash {
+				continue
+			}
+			if bytes.Compare(mineHash[:], theirs.Hash[:]) >= 0 {
+				// We already hold the value with the larger hash.
+				continue
+			}
+			log.Printf("node: anti-entropy: conflict on %v, taking value from %v (larger hash)", theirs.ID, peer)
+		}
+		d, err := node.cfg.NodeClient.Get(peer, theirs.ID)
+		if err != nil {
+			log.Printf("node: anti-entropy: fetch %v from %v failed: %v", theirs.ID, peer, err)
+			continue
+		}
+		if err := node.replace(theirs.ID, d); err != nil {
+			log.Printf("node: anti-entropy: store %v failed: %v", theirs.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package node
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"storage"
+)
+
+// defaultDedupCacheSize and defaultDedupTTL are used when
+// Config.DedupCacheSize/Config.DedupTTL are zero.
+const (
+	defaultDedupCacheSize = 4096
+	defaultDedupTTL       = 5 * time.Minute
+)
+
+// ErrIdempotencyConflict is returned by PutIdempotent when a request ID
+// is reused with a (key, payload) different from the one it was first
+// seen with.
+//
+// ErrIdempotencyConflict возвращается PutIdempotent, когда request ID
+// используется повторно с (ключом, содержимым), отличным от того, с
+// которым он был впервые увиден.
+var ErrIdempotencyConflict = errors.New("node: idempotency conflict: reqID reused with a different payload")
+
+// DedupMetrics reports cumulative counters for PutIdempotent's
+// deduplication cache.
+//
+// DedupMetrics -- накопительные счетчики кэша дедупликации
+// PutIdempotent.
+type DedupMetrics struct {
+	// Hits is the number of PutIdempotent calls resolved from the cache
+	// as a repeat of an already-applied write.
+	// Hits -- число вызовов PutIdempotent, разрешенных из кэша как
+	// повтор уже примененной записи.
+	Hits uint64
+	// Misses is the number of PutIdempotent calls for a reqID not
+	// currently in the cache.
+	// Misses -- число вызовов PutIdempotent с reqID, отсутствующим в
+	// кэше.
+	Misses uint64
+	// Conflicts is the number of PutIdempotent calls that reused a
+	// reqID with a different (key, payload).
+	// Conflicts -- число вызовов PutIdempotent, повторно
+	// использовавших reqID с другим (ключом, содержимым).
+	Conflicts uint64
+}
+
+// DedupMetrics returns the current deduplication cache counters.
+//
+// DedupMetrics возвращает текущие счетчики кэша дедупликации.
+func (node *Node) DedupMetrics() DedupMetrics {
+	return DedupMetrics{
+		Hits:      node.dedupHits.Load(),
+		Misses:    node.dedupMisses.Load(),
+		Conflicts: node.dedupConflicts.Load(),
+	}
+}
+
+// PutIdempotent behaves like Put, except repeated calls with the same
+// reqID are idempotent: a retry with the same (k, d) returns nil instead
+// of storage.ErrRecordExists, while a retry with a different (k, d)
+// returns ErrIdempotencyConflict. This lets callers retry a Put across
+// an unreliable router without the retry itself being mistaken for a
+// conflicting write.
+//
+// PutIdempotent ведет себя как Put, но повторные вызовы с тем же reqID
+// идемпотентны: повтор с тем же (k, d) возвращает nil вместо
+// storage.ErrRecordExists, а повтор с другим (k, d) -- возвращает
+// ErrIdempotencyConflict. Это позволяет вызывающей стороне повторять
+// Put при ненадежном router, не принимая сам повтор за конфликтующую
+// запись.
+func (node *Node) PutIdempotent(reqID [16]byte, k storage.RecordID, d []byte) error {
+	value := dedupValue{key: k, hash: sha256.Sum256(d)}
+
+	hit, err := node.dedup.do(reqID, value, func() error { return node.Put(k, d) })
+	switch {
+	case err == ErrIdempotencyConflict:
+		node.dedupConflicts.Add(1)
+	case hit:
+		node.dedupHits.Add(1)
+	default:
+		node.dedupMisses.Add(1)
+	}
+	return err
+}
+
+type dedupValue struct {
+	key  storage.RecordID
+	hash [32]byte
+}
+
+type dedupEntry struct {
+	reqID [16]byte
+	value dedupValue
+	exp   time.Time
+}
+
+// dedupCache is a bounded, TTL-aware LRU of recently-seen PutIdempotent
+// request IDs. It guards its own lock, which regular Put/Del traffic
+// never takes; a lookup that hits never takes node.lock, while a miss
+// holds c.mu for the duration of the write (see do).
+type dedupCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[[16]byte]*list.Element
+}
+
+func newDedupCache(size int, ttl time.Duration) *dedupCache {
+	if size <= 0 {
+		size = defaultDedupCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultDedupTTL
+	}
+	return &dedupCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[[16]byte]*list.Element),
+	}
+}
+
+// do resolves reqID against the cache and, only for a reqID not
+// currently cached, calls fn to actually perform the write. The whole
+// lookup-write-record sequence runs under c.mu so that two concurrent
+// calls for the same reqID can't both observe a miss and both call fn.
+//
+// It returns hit=true if reqID was already cached with a matching
+// value (fn was not called), or err=ErrIdempotencyConflict if reqID was
+// cached with a different value (fn was not called). Otherwise fn was
+// called and its error, if any, is returned.
+func (c *dedupCache) do(reqID [16]byte, value dedupValue, fn func() error) (hit bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[reqID]; ok {
+		e := el.Value.(*dedupEntry)
+		if time.Now().After(e.exp) {
+			c.order.Remove(el)
+			delete(c.entries, reqID)
+		} else {
+			c.order.MoveToFront(el)
+			if e.value == value {
+				return true, nil
+			}
+			return false, ErrIdempotencyConflict
+		}
+	}
+
+	if err := fn(); err != nil {
+		return false, err
+	}
+	c.insert(reqID, value)
+	return false, nil
+}
+
+// insert records reqID -> value, evicting the least-recently-used entry
+// if the cache is over size. Callers must hold c.mu.
+func (c *dedupCache) insert(reqID [16]byte, value dedupValue) {
+	exp := time.Now().Add(c.ttl)
+	if el, ok := c.entries[reqID]; ok {
+		el.Value.(*dedupEntry).value = value
+		el.Value.(*dedupEntry).exp = exp
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&dedupEntry{reqID: reqID, value: value, exp: exp})
+	c.entries[reqID] = el
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*dedupEntry).reqID)
+	}
+}
@@ -0,0 +1,43 @@
+package node
+
+import (
+	"testing"
+
+	"storage"
+)
+
+// TestPutIdempotentHitAndConflict checks the three outcomes
+// PutIdempotent documents: a miss performs the write, a repeat with the
+// same (k, d) is a no-op hit, and a repeat with a different (k, d) is a
+// rejected conflict.
+func TestPutIdempotentHitAndConflict(t *testing.T) {
+	node := newTestNode(t)
+	var reqID [16]byte
+	reqID[0] = 1
+	k := storage.RecordID("key1")
+
+	if err := node.PutIdempotent(reqID, k, []byte("value1")); err != nil {
+		t.Fatalf("PutIdempotent (miss): %v", err)
+	}
+	if got := node.DedupMetrics(); got.Misses != 1 || got.Hits != 0 || got.Conflicts != 0 {
+		t.Fatalf("metrics after miss = %+v, want Misses=1", got)
+	}
+
+	if err := node.PutIdempotent(reqID, k, []byte("value1")); err != nil {
+		t.Fatalf("PutIdempotent (hit): %v", err)
+	}
+	if got := node.DedupMetrics(); got.Hits != 1 {
+		t.Fatalf("metrics after repeat = %+v, want Hits=1", got)
+	}
+
+	if err := node.PutIdempotent(reqID, k, []byte("value2")); err != ErrIdempotencyConflict {
+		t.Fatalf("PutIdempotent (conflict): err = %v, want ErrIdempotencyConflict", err)
+	}
+	if got := node.DedupMetrics(); got.Conflicts != 1 {
+		t.Fatalf("metrics after conflict = %+v, want Conflicts=1", got)
+	}
+
+	if got, err := node.Get(k); err != nil || string(got) != "value1" {
+		t.Fatalf("Get after conflict = (%q, %v), want (\"value1\", nil)", got, err)
+	}
+}
@@ -0,0 +1,143 @@
+package node
+
+import (
+	"context"
+	"time"
+
+	"storage"
+)
+
+// defaultGCInterval is used when Config.GCInterval is zero.
+const defaultGCInterval = time.Minute
+
+// GCStats reports cumulative counters for the background TTL garbage
+// collector.
+//
+// GCStats -- накопительные счетчики фонового TTL garbage collector.
+type GCStats struct {
+	// Scanned is the number of TTL-bearing records examined across all
+	// passes.
+	// Scanned -- количество записей с TTL, осмотренных за все проходы.
+	Scanned uint64
+	// Removed is the number of expired records removed across all
+	// passes.
+	// Removed -- количество истекших записей, удаленных за все проходы.
+	Removed uint64
+}
+
+// GCStats returns the current garbage collector counters.
+//
+// GCStats возвращает текущие счетчики garbage collector.
+func (node *Node) GCStats() GCStats {
+	return GCStats{
+		Scanned: node.gcScanned.Load(),
+		Removed: node.gcRemoved.Load(),
+	}
+}
+
+// gcService is the built-in Service that periodically removes expired
+// records.
+//
+// gcService -- встроенный Service, периодически удаляющий истекшие
+// записи.
+type gcService struct {
+	node     *Node
+	interval time.Duration
+	done     chan struct{}
+}
+
+func newGCService(node *Node) (Service, error) {
+	interval := node.cfg.GCInterval
+	if interval <= 0 {
+		interval = defaultGCInterval
+	}
+	return &gcService{node: node, interval: interval}, nil
+}
+
+// Start implements Service.
+func (g *gcService) Start(ctx context.Context) error {
+	g.done = make(chan struct{})
+	go func() {
+		defer close(g.done)
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if g.node.Mode() != ModeReadWrite {
+					continue
+				}
+				g.runPass(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop implements Service.
+func (g *gcService) Stop() error {
+	<-g.done
+	return nil
+}
+
+// runPass scans node.expiry for expired records and removes them. It is
+// cancellable mid-scan via node.SetMode, and its deferred cleanup runs
+// even if a handler panics, so a single bad record can't wedge future
+// passes.
+func (g *gcService) runPass(parent context.Context) {
+	passCtx, cancel := context.WithCancel(parent)
+	node := g.node
+
+	node.lock.Lock()
+	node.gcPassCancel = cancel
+	node.lock.Unlock()
+
+	defer func() {
+		node.lock.Lock()
+		node.gcPassCancel = nil
+		node.lock.Unlock()
+		cancel()
+		recover() // a panicking handler must not wedge the next pass
+	}()
+
+	now := time.Now()
+
+	node.lock.Lock()
+	expired := make([]storage.RecordID, 0)
+	for k, exp := range node.expiry {
+		if passCtx.Err() != nil {
+			node.lock.Unlock()
+			return
+		}
+		node.gcScanned.Add(1)
+		if !now.Before(exp) {
+			expired = append(expired, k)
+		}
+	}
+	node.lock.Unlock()
+
+	for _, k := range expired {
+		if passCtx.Err() != nil {
+			return
+		}
+		func() {
+			// Unlock via defer so a panic here releases node.lock before
+			// the pass's deferred cleanup takes it again.
+			node.lock.Lock()
+			defer node.lock.Unlock()
+			if exp, ok := node.expiry[k]; ok && !now.Before(exp) {
+				if raw, err := node.backend.Get(k); err == nil {
+					if d, _, err := decodeRecord(raw); err == nil {
+						if err := node.backend.Del(k); err == nil {
+							node.gcRemoved.Add(1)
+							node.xorDigest(k, d)
+						}
+					}
+				}
+				delete(node.expiry, k)
+			}
+		}()
+	}
+}
@@ -0,0 +1,47 @@
+package node
+
+import (
+	"context"
+	"time"
+)
+
+// heartbeatService is the built-in Service that sends heartbeats from a
+// Node to its router every cfg.Heartbeat interval.
+//
+// heartbeatService -- встроенный Service, отправляющий heartbeats от
+// Node к router через каждый интервал cfg.Heartbeat.
+type heartbeatService struct {
+	node *Node
+	done chan struct{}
+}
+
+func newHeartbeatService(node *Node) (Service, error) {
+	return &heartbeatService{node: node}, nil
+}
+
+// Start implements Service.
+func (h *heartbeatService) Start(ctx context.Context) error {
+	h.done = make(chan struct{})
+	go func() {
+		defer close(h.done)
+		h.node.cfg.Client.Heartbeat(h.node.cfg.Router, h.node.cfg.Addr)
+		ticker := time.NewTicker(h.node.cfg.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.node.cfg.Client.Heartbeat(h.node.cfg.Router, h.node.cfg.Addr)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop implements Service. It blocks until the heartbeat goroutine has
+// observed ctx cancellation and exited.
+func (h *heartbeatService) Stop() error {
+	<-h.done
+	return nil
+}
@@ -0,0 +1,63 @@
+package node
+
+import "errors"
+
+// Mode is an operating mode of a Node.
+//
+// Mode -- режим работы Node.
+type Mode int
+
+const (
+	// ModeReadWrite is the normal operating mode: reads and writes are
+	// both served, and the background TTL garbage collector runs.
+	// ModeReadWrite -- обычный режим работы: обслуживаются и чтение, и
+	// запись, и работает фоновый TTL garbage collector.
+	ModeReadWrite Mode = iota
+	// ModeReadOnly serves reads but rejects writes with ErrNotWritable,
+	// e.g. while the node is being drained. The garbage collector does
+	// not run in this mode.
+	// ModeReadOnly -- обслуживает чтение, но отклоняет запись с ошибкой
+	// ErrNotWritable, например во время вывода узла из эксплуатации.
+	// Garbage collector в этом режиме не работает.
+	ModeReadOnly
+	// ModeDegraded indicates the node's backend is only partially
+	// available: like ModeReadOnly, writes are rejected with
+	// ErrNotWritable and the garbage collector does not run.
+	// ModeDegraded -- backend узла доступен только частично: как и в
+	// ModeReadOnly, запись отклоняется с ошибкой ErrNotWritable, а
+	// garbage collector не работает.
+	ModeDegraded
+)
+
+// ErrNotWritable is returned by Put/PutWithTTL/Del when the Node is not
+// in ModeReadWrite.
+//
+// ErrNotWritable возвращается Put/PutWithTTL/Del, когда Node не
+// находится в режиме ModeReadWrite.
+var ErrNotWritable = errors.New("node: writes are rejected outside ModeReadWrite")
+
+// SetMode changes the Node's operating mode. If a garbage collection
+// pass is currently in flight, it is cancelled immediately so that
+// operators can quiesce a Node before draining it.
+//
+// SetMode меняет режим работы Node. Если в данный момент выполняется
+// проход garbage collection, он немедленно отменяется, чтобы оператор
+// мог приостановить Node перед выводом из эксплуатации.
+func (node *Node) SetMode(mode Mode) {
+	node.lock.Lock()
+	node.mode = mode
+	cancel := node.gcPassCancel
+	node.lock.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Mode returns the Node's current operating mode.
+//
+// Mode возвращает текущий режим работы Node.
+func (node *Node) Mode() Mode {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.mode
+}
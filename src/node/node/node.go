@@ -1,9 +1,17 @@
 package node
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"node/backend"
+	"node/backend/memory"
+	"node/client"
 	router "router/client"
 	"storage"
 )
@@ -22,94 +30,349 @@ type Config struct {
 	// Heartbeat -- интервал между двумя heartbeats.
 	Heartbeat time.Duration
 
-	// Client specifies client for Router.
-	// Client -- клиент для Router.
+	// Client specifies client for Router. Besides Heartbeat, replication
+	// (replicate.go) uses NodesFind to look up replica holders for a key,
+	// and anti-entropy (antientropy.go) uses List to discover sibling
+	// Nodes to reconcile against.
+	// Client -- клиент для Router. Помимо Heartbeat, репликация
+	// (replicate.go) использует NodesFind для поиска узлов-держателей
+	// реплик по ключу, а anti-entropy (antientropy.go) использует List
+	// для поиска соседних Node для сверки.
 	Client router.Client `yaml:"-"`
-}
 
-// Node is a Node service.
-type Node struct {
-	lock sync.Mutex
-	cfg  Config
-	test chan struct{}
-	data map[storage.RecordID][]byte
-}
+	// Backend specifies the storage backend to persist records to. If
+	// nil, New defaults to an in-memory backend.Backend that does not
+	// survive a restart.
+	// Backend -- backend для хранения записей. Если nil, New использует
+	// backend в памяти, не переживающий перезапуск.
+	Backend backend.Backend `yaml:"-"`
 
-// New creates a new Node with a given cfg.
-//
-// New создает новый Node с данным cfg.
-func New(cfg Config) *Node {
-	return &Node{lock: sync.Mutex{}, cfg: cfg, test: make(chan struct{}), data: make(map[storage.RecordID][]byte)}
+	// GCInterval is a time interval between two passes of the TTL
+	// garbage collector. If zero, defaultGCInterval is used.
+	// GCInterval -- интервал между двумя проходами TTL garbage
+	// collector. Если не задан, используется defaultGCInterval.
+	GCInterval time.Duration
+
+	// Replicas is a default replication factor. Node itself does not
+	// read it: PutN and DelN take the number of copies as an argument,
+	// and anti-entropy reconciles against a single peer.
+	// Replicas -- коэффициент репликации по умолчанию. Сам Node его не
+	// читает: PutN и DelN получают число копий аргументом, а anti-entropy
+	// сверяется с одним peer.
+	Replicas int
+
+	// ReplicaTimeout bounds how long PutN/DelN wait for acks from sibling
+	// Nodes. If zero, defaultReplicaTimeout is used.
+	// ReplicaTimeout -- ограничивает время ожидания PutN/DelN
+	// подтверждений от соседних Node. Если не задан, используется
+	// defaultReplicaTimeout.
+	ReplicaTimeout time.Duration
+
+	// NodeClient is used to issue Put/Del RPCs to sibling Nodes for
+	// replicated writes.
+	// NodeClient -- используется для Put/Del RPC к соседним Node при
+	// репликации записи.
+	NodeClient client.NodeClient `yaml:"-"`
+
+	// AntiEntropyInterval is a time interval between two passes of the
+	// anti-entropy sync. If zero, defaultAntiEntropyInterval is used.
+	// AntiEntropyInterval -- интервал между двумя проходами anti-entropy
+	// sync. Если не задан, используется defaultAntiEntropyInterval.
+	AntiEntropyInterval time.Duration
+
+	// DedupCacheSize bounds the number of recent PutIdempotent request
+	// IDs remembered at once. If zero, defaultDedupCacheSize is used.
+	// DedupCacheSize -- ограничивает число недавних request ID
+	// PutIdempotent, хранимых одновременно. Если не задан, используется
+	// defaultDedupCacheSize.
+	DedupCacheSize int
+
+	// DedupTTL is how long a PutIdempotent request ID is remembered. If
+	// zero, defaultDedupTTL is used.
+	// DedupTTL -- как долго помнится request ID PutIdempotent. Если не
+	// задан, используется defaultDedupTTL.
+	DedupTTL time.Duration
 }
 
-// Heartbeats runs heartbeats from node to a router
-// each time interval set by cfg.Heartbeat.
+// Node is a Node service. Besides storing records, it acts as a
+// lifecycle container for pluggable Services registered with
+// RegisterService; see service.go.
 //
-// Heartbeats запускает отправку heartbeats от node к router
-// через каждый интервал времени, заданный в cfg.Heartbeat.
-func (node *Node) Heartbeats() {
-	go startHb(node)
-}
+// Node -- Node service. Помимо хранения записей, Node выступает
+// контейнером жизненного цикла для подключаемых Service,
+// зарегистрированных через RegisterService; см. service.go.
+type Node struct {
+	lock    sync.Mutex
+	cfg     Config
+	backend backend.Backend
+	mode    Mode
+	expiry  map[storage.RecordID]time.Time
 
-func startHb(node *Node) {
-	for {
-		select {
-		case <-node.test:
-			return
-		default:
-			node.cfg.Client.Heartbeat(node.cfg.Router, node.cfg.Addr)
-			time.Sleep(node.cfg.Heartbeat)
-		}
-	}
+	// bucketDigest[b] is the running XOR of sha256(value) over every
+	// record currently in the backend whose key hashes into bucket b;
+	// see antientropy.go.
+	bucketDigest [numBuckets][32]byte
+
+	gcScanned    atomic.Uint64
+	gcRemoved    atomic.Uint64
+	gcPassCancel context.CancelFunc
+
+	// dedup has its own lock, separate from node.lock: regular Put/Del
+	// never take it, and a PutIdempotent cache hit never takes
+	// node.lock. A cache miss holds dedup's lock while its write waits
+	// for node.lock.
+	dedup          *dedupCache
+	dedupHits      atomic.Uint64
+	dedupMisses    atomic.Uint64
+	dedupConflicts atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	ctors  []namedCtor
+	active []namedService
 }
 
-// Stop stops heartbeats
+// New creates a new Node with a given cfg. If cfg.Backend is set, New
+// validates it by iterating over every record already in the backend,
+// so that records written before a crash are available -- folded into
+// bucketDigest for anti-entropy and, for records with a TTL still
+// pending, restored into expiry -- before the first heartbeat is sent
+// to the router.
 //
-// Stop останавливает отправку heartbeats.
-func (node *Node) Stop() {
-	node.test <- struct{}{}
+// New создает новый Node с данным cfg. Если cfg.Backend задан, New
+// проверяет его работоспособность, итерируя по всем уже имеющимся в
+// backend записям, чтобы записи, сделанные до сбоя, были доступны --
+// учтены в bucketDigest для anti-entropy, а для записей с еще не
+// истекшим TTL восстановлены в expiry -- еще до отправки первого
+// heartbeat в router.
+func New(cfg Config) (*Node, error) {
+	b := cfg.Backend
+	if b == nil {
+		b = memory.New()
+	}
+	node := &Node{
+		cfg:     cfg,
+		backend: b,
+		expiry:  make(map[storage.RecordID]time.Time),
+		dedup:   newDedupCache(cfg.DedupCacheSize, cfg.DedupTTL),
+	}
+	if err := b.Iterate(func(k storage.RecordID, raw []byte) error {
+		d, exp, err := decodeRecord(raw)
+		if err != nil {
+			return err
+		}
+		node.xorDigest(k, d)
+		if !exp.IsZero() {
+			node.expiry[k] = exp
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	// Heartbeats and the TTL garbage collector are built-in Services so
+	// that users who register their own Services (metrics exporters,
+	// replication daemons, admin RPC servers, ...) get the same
+	// Start/Stop semantics.
+	//
+	// Heartbeats и TTL garbage collector -- встроенные Service, чтобы
+	// пользователи, регистрирующие свои Service (экспортеры метрик,
+	// репликация, admin RPC, ...), получали те же семантики Start/Stop.
+	if err := node.RegisterService("heartbeat", newHeartbeatService); err != nil {
+		return nil, err
+	}
+	if err := node.RegisterService("gc", newGCService); err != nil {
+		return nil, err
+	}
+	if err := node.RegisterService("anti-entropy", newAntiEntropyService); err != nil {
+		return nil, err
+	}
+	return node, nil
 }
 
 // Put an item to the node if an item for the given key doesn't exist.
-// Returns the storage.ErrRecordExists error otherwise.
+// Returns the storage.ErrRecordExists error otherwise, or ErrNotWritable
+// if the node isn't in ModeReadWrite. The item never expires; see
+// PutWithTTL for records with a lifetime.
 //
 // Put -- добавить запись в node, если запись для данного ключа
-// не существует. Иначе вернуть ошибку storage.ErrRecordExists.
+// не существует. Иначе вернуть ошибку storage.ErrRecordExists, либо
+// ErrNotWritable, если node не в режиме ModeReadWrite. Запись
+// никогда не истекает; см. PutWithTTL для записей с временем жизни.
 func (node *Node) Put(k storage.RecordID, d []byte) error {
 	node.lock.Lock()
 	defer node.lock.Unlock()
-	if _, ok := node.data[k]; !ok {
-		node.data[k] = d
-		return nil
+	if node.mode != ModeReadWrite {
+		return ErrNotWritable
 	}
-	return storage.ErrRecordExists
+	if _, err := node.getLocked(k); err == nil {
+		return storage.ErrRecordExists
+	}
+	delete(node.expiry, k)
+	return node.storeLocked(k, d, time.Time{})
+}
+
+// PutWithTTL behaves like Put, but the record is considered expired --
+// and is eventually removed by the background garbage collector -- once
+// ttl has elapsed since the call.
+//
+// PutWithTTL ведет себя как Put, но запись считается истекшей -- и в
+// конце концов удаляется фоновым garbage collector -- по прошествии ttl
+// с момента вызова.
+func (node *Node) PutWithTTL(k storage.RecordID, d []byte, ttl time.Duration) error {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.mode != ModeReadWrite {
+		return ErrNotWritable
+	}
+	if _, err := node.getLocked(k); err == nil {
+		return storage.ErrRecordExists
+	}
+	exp := time.Now().Add(ttl)
+	if err := node.storeLocked(k, d, exp); err != nil {
+		return err
+	}
+	node.expiry[k] = exp
+	return nil
 }
 
 // Del an item from the node if an item exists for the given key.
-// Returns the storage.ErrRecordNotFound error otherwise.
+// Returns the storage.ErrRecordNotFound error otherwise, or
+// ErrNotWritable if the node isn't in ModeReadWrite.
 //
 // Del -- удалить запись из node, если запись для данного ключа
-// существует. Иначе вернуть ошибку storage.ErrRecordNotFound.
+// существует. Иначе вернуть ошибку storage.ErrRecordNotFound, либо
+// ErrNotWritable, если node не в режиме ModeReadWrite.
 func (node *Node) Del(k storage.RecordID) error {
 	node.lock.Lock()
 	defer node.lock.Unlock()
-	if _, ok := node.data[k]; ok {
-		delete(node.data, k)
-		return nil
+	if node.mode != ModeReadWrite {
+		return ErrNotWritable
 	}
-	return storage.ErrRecordNotFound
+	d, err := node.getLocked(k)
+	if err != nil {
+		return err
+	}
+	delete(node.expiry, k)
+	if err := node.backend.Del(k); err != nil {
+		return err
+	}
+	node.xorDigest(k, d)
+	return nil
 }
 
 // Get an item from the node if an item exists for the given key.
-// Returns the storage.ErrRecordNotFound error otherwise.
+// Returns the storage.ErrRecordNotFound error otherwise. A record whose
+// TTL has elapsed but that the garbage collector hasn't removed yet is
+// treated as not found.
 //
 // Get -- получить запись из node, если запись для данного ключа
-// существует. Иначе вернуть ошибку storage.ErrRecordNotFound.
+// существует. Иначе вернуть ошибку storage.ErrRecordNotFound. Запись,
+// чей TTL истек, но которую еще не удалил garbage collector, считается
+// не найденной.
 func (node *Node) Get(k storage.RecordID) ([]byte, error) {
 	node.lock.Lock()
 	defer node.lock.Unlock()
-	if d, ok := node.data[k]; ok {
-		return d, nil
+	return node.getLocked(k)
+}
+
+// getLocked is the shared implementation of Get's expired-entry masking.
+// Callers must hold node.lock.
+func (node *Node) getLocked(k storage.RecordID) ([]byte, error) {
+	if exp, ok := node.expiry[k]; ok && !time.Now().Before(exp) {
+		return nil, storage.ErrRecordNotFound
+	}
+	raw, err := node.backend.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	d, _, err := decodeRecord(raw)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// storeLocked writes d under k to the backend, alongside exp (the zero
+// Time if the record has no TTL) so a restart can restore expiry from
+// the backend alone; see decodeRecord. It keeps bucketDigest consistent
+// with the backend's physical contents: if k already holds a value --
+// including one only masked as absent by getLocked's expiry check --
+// its hash is XORed out before d's hash is XORed in. Callers must hold
+// node.lock.
+func (node *Node) storeLocked(k storage.RecordID, d []byte, exp time.Time) error {
+	if old, err := node.backend.Get(k); err == nil {
+		if oldData, _, err := decodeRecord(old); err == nil {
+			node.xorDigest(k, oldData)
+		}
+	}
+	enc, err := encodeRecord(d, exp)
+	if err != nil {
+		return err
+	}
+	if err := node.backend.Put(k, enc); err != nil {
+		return err
+	}
+	node.xorDigest(k, d)
+	return nil
+}
+
+// replace stores d under k unconditionally, overwriting any existing
+// value. Unlike Put, it never returns storage.ErrRecordExists; it is
+// used by anti-entropy sync to pull missing or conflicting records from
+// a sibling Node.
+//
+// replace always clears k's expiry: client.NodeClient carries no TTL
+// alongside a record's value, so a record pulled from a peer becomes
+// immortal on this Node even if it still had a TTL pending there. This
+// is a known limitation of the current wire format, not an oversight;
+// fixing it requires propagating expiry through client.NodeClient.
+//
+// replace всегда очищает expiry для k: client.NodeClient не передает
+// TTL вместе со значением записи, поэтому запись, полученная от peer,
+// становится бессрочной на этом Node, даже если там у нее еще оставался
+// TTL. Это известное ограничение текущего формата передачи, а не
+// недосмотр; исправление требует передачи expiry через
+// client.NodeClient.
+func (node *Node) replace(k storage.RecordID, d []byte) error {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if err := node.storeLocked(k, d, time.Time{}); err != nil {
+		return err
+	}
+	delete(node.expiry, k)
+	return nil
+}
+
+// record is the envelope storeLocked writes to the backend: the value
+// plus the expiry it was stored with, if any. This lets expiry survive
+// a restart for backends that persist to disk, without requiring every
+// backend.Backend implementation to know about TTL.
+//
+// record -- конверт, который storeLocked записывает в backend: значение
+// плюс срок действия, с которым оно было сохранено, если он был. Это
+// позволяет TTL пережить перезапуск для backend, сохраняющих данные на
+// диск, не требуя от каждой реализации backend.Backend знания о TTL.
+type record struct {
+	Data []byte
+	Exp  time.Time
+}
+
+// encodeRecord wraps d and exp (the zero Time for no TTL) into the byte
+// slice storeLocked passes to backend.Put.
+func encodeRecord(d []byte, exp time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record{Data: d, Exp: exp}); err != nil {
+		return nil, fmt.Errorf("node: encode record: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRecord reverses encodeRecord. A zero exp means the record has
+// no TTL.
+func decodeRecord(raw []byte) (d []byte, exp time.Time, err error) {
+	var r record
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&r); err != nil {
+		return nil, time.Time{}, fmt.Errorf("node: decode record: %w", err)
 	}
-	return nil, storage.ErrRecordNotFound
+	return r.Data, r.Exp, nil
 }
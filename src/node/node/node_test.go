@@ -0,0 +1,66 @@
+package node
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"storage"
+)
+
+func newTestNode(t *testing.T) *Node {
+	t.Helper()
+	n, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return n
+}
+
+// TestDigestRoundTrip checks that Put followed by Del returns the
+// affected bucket's digest to all-zero, i.e. that xorDigest's two calls
+// per record (XOR in on Put, XOR out on Del) cancel out exactly.
+func TestDigestRoundTrip(t *testing.T) {
+	node := newTestNode(t)
+	k := storage.RecordID("key1")
+
+	if err := node.Put(k, []byte("value1")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	b := bucketOf(k)
+	if bytes.Equal(node.BucketDigests()[b], make([]byte, 32)) {
+		t.Fatalf("bucket %d digest is zero after Put", b)
+	}
+
+	if err := node.Del(k); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if got := node.BucketDigests()[b]; !bytes.Equal(got, make([]byte, 32)) {
+		t.Fatalf("bucket %d digest = %x, want all-zero after Del", b, got)
+	}
+}
+
+// TestDigestOverwriteExpired checks that Put over a key whose previous
+// value is still physically present but masked as expired replaces the
+// old hash in the bucket digest instead of XORing the new hash in on
+// top of it (see storeLocked).
+func TestDigestOverwriteExpired(t *testing.T) {
+	node := newTestNode(t)
+	k := storage.RecordID("key1")
+
+	if err := node.PutWithTTL(k, []byte("old"), -time.Second); err != nil {
+		t.Fatalf("PutWithTTL: %v", err)
+	}
+	if err := node.Put(k, []byte("new")); err != nil {
+		t.Fatalf("Put over expired key: %v", err)
+	}
+
+	want := &Node{}
+	want.bucketDigest = [numBuckets][32]byte{}
+	want.xorDigest(k, []byte("new"))
+
+	b := bucketOf(k)
+	if got := node.BucketDigests()[b]; !bytes.Equal(got, want.bucketDigest[b][:]) {
+		t.Fatalf("bucket %d digest = %x, want %x (hash of the new value alone)", b, got, want.bucketDigest[b][:])
+	}
+}
@@ -0,0 +1,165 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"storage"
+)
+
+// defaultReplicaTimeout is used when Config.ReplicaTimeout is zero.
+const defaultReplicaTimeout = 5 * time.Second
+
+// PartialWriteError is returned by PutN/DelN when fewer than the
+// requested number of sibling Nodes acknowledged the write before the
+// deadline.
+//
+// PartialWriteError возвращается PutN/DelN, когда до дедлайна
+// подтвердило запись меньше соседних Node, чем было запрошено.
+type PartialWriteError struct {
+	// Want is the number of sibling acks that were required: the copies
+	// requested, less one for each entry the router returned that was
+	// this Node itself.
+	// Want -- требуемое число подтверждений от соседних Node: запрошенное
+	// число копий минус по одной за каждую запись router, указывающую на
+	// сам этот Node.
+	Want int
+	// Acked lists the addresses of sibling Nodes that accepted the
+	// write.
+	// Acked -- адреса соседних Node, принявших запись.
+	Acked []storage.ServiceAddr
+}
+
+// Error implements error.
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("node: partial write: %d/%d replicas acked (%v)", len(e.Acked), e.Want, e.Acked)
+}
+
+// PutN stores d under k locally and fans it out to copies sibling Nodes
+// obtained from the router, returning once at least copies of them have
+// acknowledged the write within Config.ReplicaTimeout. If the router
+// lists this Node itself, the local write counts as that entry's ack.
+// If fewer did, it returns a *PartialWriteError listing which peers
+// accepted the write.
+//
+// PutN has no TTL-aware counterpart: it always writes through Put, so a
+// record stored via PutWithTTL and then replicated with PutN would lose
+// its TTL on every sibling it reaches. client.NodeClient.Put carries no
+// TTL, so this is a limitation of the current wire format rather than a
+// bug in the fan-out logic; propagating TTL requires extending
+// client.NodeClient.
+//
+// PutN сохраняет d под ключом k локально и рассылает запись copies
+// соседним Node, полученным от router, возвращаясь, как только хотя бы
+// copies из них подтвердят запись в пределах Config.ReplicaTimeout. Если
+// router указывает сам этот Node, локальная запись считается его
+// подтверждением. Если подтвердило меньше, возвращается
+// *PartialWriteError со списком
+// принявших запись узлов.
+//
+// У PutN нет TTL-версии: запись всегда идет через Put, поэтому запись,
+// сохраненная через PutWithTTL и затем реплицированная PutN, теряет TTL
+// на каждом соседнем узле. client.NodeClient.Put не передает TTL,
+// поэтому это ограничение текущего формата передачи, а не ошибка
+// логики fan-out; для передачи TTL потребуется расширить
+// client.NodeClient.
+func (node *Node) PutN(k storage.RecordID, d []byte, copies int) error {
+	if err := node.Put(k, d); err != nil {
+		return err
+	}
+	peers, err := node.cfg.Client.NodesFind(k, copies)
+	if err != nil {
+		return err
+	}
+	// NodesFind may legitimately include this Node's own address (see
+	// excludeSelf); want must shrink by however many self-entries it
+	// removed, or quorum could become unreachable once the local write
+	// is excluded from the fan-out.
+	filtered := node.excludeSelf(peers)
+	want := copies - (len(peers) - len(filtered))
+	return node.fanOut(filtered, want, func(addr storage.ServiceAddr) error {
+		return node.cfg.NodeClient.Put(addr, k, d)
+	})
+}
+
+// DelN mirrors PutN for deletion: it deletes the record locally and
+// fans the deletion out to copies sibling Nodes.
+//
+// DelN повторяет PutN для удаления: удаляет запись локально и
+// рассылает удаление copies соседним Node.
+func (node *Node) DelN(k storage.RecordID, copies int) error {
+	if err := node.Del(k); err != nil {
+		return err
+	}
+	peers, err := node.cfg.Client.NodesFind(k, copies)
+	if err != nil {
+		return err
+	}
+	// See the matching comment in PutN: want must shrink by however many
+	// self-entries excludeSelf removed.
+	filtered := node.excludeSelf(peers)
+	want := copies - (len(peers) - len(filtered))
+	return node.fanOut(filtered, want, func(addr storage.ServiceAddr) error {
+		return node.cfg.NodeClient.Del(addr, k)
+	})
+}
+
+// excludeSelf drops node.cfg.Addr from peers. The router may legitimately
+// return this Node itself as one of the replica holders for k; PutN/DelN
+// already applied the write locally, so re-issuing it as a self-directed
+// RPC would only bounce off storage.ErrRecordExists/ErrRecordNotFound and
+// be miscounted as a peer that failed to ack.
+func (node *Node) excludeSelf(peers []storage.ServiceAddr) []storage.ServiceAddr {
+	out := make([]storage.ServiceAddr, 0, len(peers))
+	for _, peer := range peers {
+		if peer != node.cfg.Addr {
+			out = append(out, peer)
+		}
+	}
+	return out
+}
+
+// fanOut calls op concurrently for every peer and returns as soon as
+// want of them succeed (quorum), without waiting for stragglers, or
+// returns a *PartialWriteError once the replica deadline passes without
+// reaching want acks.
+func (node *Node) fanOut(peers []storage.ServiceAddr, want int, op func(storage.ServiceAddr) error) error {
+	timeout := node.cfg.ReplicaTimeout
+	if timeout <= 0 {
+		timeout = defaultReplicaTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type ack struct {
+		addr storage.ServiceAddr
+		err  error
+	}
+	acks := make(chan ack, len(peers))
+	for _, peer := range peers {
+		peer := peer
+		go func() {
+			acks <- ack{addr: peer, err: op(peer)}
+		}()
+	}
+	if want <= 0 {
+		return nil
+	}
+
+	var acked []storage.ServiceAddr
+	for range peers {
+		select {
+		case a := <-acks:
+			if a.err == nil {
+				acked = append(acked, a.addr)
+				if len(acked) >= want {
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			return &PartialWriteError{Want: want, Acked: acked}
+		}
+	}
+	return &PartialWriteError{Want: want, Acked: acked}
+}
@@ -0,0 +1,109 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Service is a component that runs alongside a Node for as long as the
+// Node is running. Start must not block: it should launch whatever
+// goroutines it needs and return. Stop must block until those
+// goroutines have fully exited.
+//
+// Service -- компонент, работающий наряду с Node все время её работы.
+// Start не должен блокироваться: он должен запустить необходимые
+// горутины и вернуть управление. Stop должен блокироваться, пока эти
+// горутины полностью не завершатся.
+type Service interface {
+	// Start launches the service. ctx is cancelled when the owning Node
+	// is stopped; the service should treat that as a shutdown signal.
+	// Start -- запустить service. ctx отменяется при остановке
+	// владеющего Node; service должен воспринимать это как сигнал
+	// остановки.
+	Start(ctx context.Context) error
+
+	// Stop blocks until the service has finished shutting down.
+	// Stop -- блокируется, пока service не завершит остановку.
+	Stop() error
+}
+
+type namedCtor struct {
+	name string
+	ctor func(*Node) (Service, error)
+}
+
+type namedService struct {
+	name string
+	svc  Service
+}
+
+// RegisterService registers a constructor for a Service under name. The
+// service is instantiated and started when Start is called, in
+// registration order. RegisterService fails if the Node has already
+// been started.
+//
+// RegisterService регистрирует конструктор Service под именем name.
+// Service создается и запускается при вызове Start, в порядке
+// регистрации. RegisterService вернет ошибку, если Node уже запущен.
+func (node *Node) RegisterService(name string, ctor func(*Node) (Service, error)) error {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.cancel != nil {
+		return fmt.Errorf("node: cannot register service %q: node already started", name)
+	}
+	node.ctors = append(node.ctors, namedCtor{name: name, ctor: ctor})
+	return nil
+}
+
+// Start instantiates and starts every registered Service, including the
+// built-in heartbeat service. All services share a context derived from
+// ctx; it is cancelled when Stop is called.
+//
+// Start создает и запускает все зарегистрированные Service, включая
+// встроенный heartbeat service. Все services разделяют context,
+// производный от ctx; он отменяется при вызове Stop.
+func (node *Node) Start(ctx context.Context) error {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.cancel != nil {
+		return errors.New("node: already started")
+	}
+	node.ctx, node.cancel = context.WithCancel(ctx)
+	for _, c := range node.ctors {
+		svc, err := c.ctor(node)
+		if err != nil {
+			return fmt.Errorf("node: %s: %w", c.name, err)
+		}
+		if err := svc.Start(node.ctx); err != nil {
+			return fmt.Errorf("node: %s: %w", c.name, err)
+		}
+		node.active = append(node.active, namedService{name: c.name, svc: svc})
+	}
+	return nil
+}
+
+// Stop cancels the shared context, waits for every running Service to
+// shut down and returns their joined errors, if any.
+//
+// Stop отменяет общий context, дожидается остановки всех запущенных
+// Service и возвращает объединение их ошибок, если они есть.
+func (node *Node) Stop() error {
+	node.lock.Lock()
+	cancel := node.cancel
+	active := node.active
+	node.lock.Unlock()
+
+	if cancel == nil {
+		return errors.New("node: not started")
+	}
+	cancel()
+
+	var errs []error
+	for _, s := range active {
+		if err := s.svc.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}